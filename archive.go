@@ -0,0 +1,269 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// openInputFS resolves the scan target, which may be a directory or a
+// .zip/.tar/.tar.gz/.tar.bz2 archive, into an fs.FS that filepath walking
+// and file reading can use unchanged either way. rootDirAbs is the
+// absolute path of the input when it's a plain directory, used to
+// recognize and skip the output file if it lives inside the tree being
+// scanned; it's empty for archive inputs, which can never contain it.
+func openInputFS(inputPath string) (fsys fs.FS, displayRoot string, rootDirAbs string, cleanup func() error, err error) {
+	info, err := os.Stat(inputPath)
+	if err != nil {
+		return nil, "", "", nil, fmt.Errorf("error accessing input path %q: %w", inputPath, err)
+	}
+
+	if info.IsDir() {
+		absDir, err := filepath.Abs(inputPath)
+		if err != nil {
+			return nil, "", "", nil, fmt.Errorf("error resolving input directory: %w", err)
+		}
+		return os.DirFS(absDir), absDir, absDir, func() error { return nil }, nil
+	}
+
+	switch archiveExt(inputPath) {
+	case ".zip":
+		fsys, cleanup, err := openZipFS(inputPath)
+		return fsys, inputPath, "", cleanup, err
+	case ".tar", ".tar.gz", ".tgz", ".tar.bz2":
+		fsys, cleanup, err := openTarFS(inputPath)
+		return fsys, inputPath, "", cleanup, err
+	default:
+		return nil, "", "", nil, fmt.Errorf("unsupported input %q: expected a directory or a .zip/.tar/.tar.gz/.tar.bz2 archive", inputPath)
+	}
+}
+
+// archiveExt returns the archive-style extension of p, treating ".tar.gz"
+// and ".tar.bz2" as a single unit rather than just ".gz"/".bz2".
+func archiveExt(p string) string {
+	lower := strings.ToLower(p)
+	for _, ext := range []string{".tar.gz", ".tar.bz2"} {
+		if strings.HasSuffix(lower, ext) {
+			return ext
+		}
+	}
+	return strings.ToLower(filepath.Ext(p))
+}
+
+// openZipFS opens a .zip archive. *zip.Reader implements fs.FS directly,
+// so no adapter is needed.
+func openZipFS(zipPath string) (fs.FS, func() error, error) {
+	f, err := os.Open(zipPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error opening archive %q: %w", zipPath, err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("error reading archive %q: %w", zipPath, err)
+	}
+
+	zr, err := zip.NewReader(f, info.Size())
+	if err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("error reading zip archive %q: %w", zipPath, err)
+	}
+	return zr, f.Close, nil
+}
+
+// openTarFS reads a .tar/.tar.gz/.tar.bz2 archive fully into a small
+// in-memory fs.FS, since archive/tar only offers sequential access and
+// has no fs.FS adapter of its own.
+func openTarFS(tarPath string) (fs.FS, func() error, error) {
+	f, err := os.Open(tarPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error opening archive %q: %w", tarPath, err)
+	}
+	defer f.Close()
+
+	var reader io.Reader = f
+	switch archiveExt(tarPath) {
+	case ".tar.gz", ".tgz":
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading gzip archive %q: %w", tarPath, err)
+		}
+		defer gz.Close()
+		reader = gz
+	case ".tar.bz2":
+		reader = bzip2.NewReader(f)
+	}
+
+	mfs := newMemFS()
+	tr := tar.NewReader(reader)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading tar entry in %q: %w", tarPath, err)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			mfs.addDir(hdr.Name)
+		case tar.TypeReg:
+			content, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, nil, fmt.Errorf("error reading tar entry %q in %q: %w", hdr.Name, tarPath, err)
+			}
+			mfs.addFile(hdr.Name, content, hdr.ModTime)
+		}
+	}
+	return mfs, func() error { return nil }, nil
+}
+
+// memFS is a minimal read-only in-memory fs.FS backing tar-based
+// archives, which have no native fs.FS implementation to wrap.
+type memFS struct {
+	files    map[string]*memFileEntry
+	children map[string][]string
+}
+
+func newMemFS() *memFS {
+	return &memFS{
+		files:    map[string]*memFileEntry{"": {name: ".", isDir: true}},
+		children: map[string][]string{},
+	}
+}
+
+func (m *memFS) addDir(name string) {
+	if p := cleanEntryPath(name); p != "" {
+		m.ensureDir(p)
+	}
+}
+
+func (m *memFS) addFile(name string, content []byte, modTime time.Time) {
+	p := cleanEntryPath(name)
+	if p == "" {
+		return
+	}
+	parent := parentOf(p)
+	m.ensureDir(parent)
+	if _, exists := m.files[p]; !exists {
+		m.children[parent] = append(m.children[parent], p)
+	}
+	m.files[p] = &memFileEntry{name: path.Base(p), content: content, modTime: modTime}
+}
+
+func (m *memFS) ensureDir(p string) {
+	if p == "" {
+		return
+	}
+	if _, ok := m.files[p]; ok {
+		return
+	}
+	parent := parentOf(p)
+	m.ensureDir(parent)
+	m.files[p] = &memFileEntry{name: path.Base(p), isDir: true}
+	m.children[parent] = append(m.children[parent], p)
+}
+
+func parentOf(p string) string {
+	if dir := path.Dir(p); dir != "." {
+		return dir
+	}
+	return ""
+}
+
+// cleanEntryPath normalizes an archive entry name (which may use "./"
+// prefixes, backslashes, or trailing slashes) into a clean, slash-separated
+// path relative to the archive root with no leading or trailing slash.
+func cleanEntryPath(p string) string {
+	p = filepath.ToSlash(p)
+	cleaned := path.Clean("/" + p)
+	return strings.TrimPrefix(cleaned, "/")
+}
+
+func (m *memFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	entry, ok := m.files[lookupKey(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if entry.isDir {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	return &memOpenFile{entry: entry, Reader: bytes.NewReader(entry.content)}, nil
+}
+
+func (m *memFS) Stat(name string) (fs.FileInfo, error) {
+	entry, ok := m.files[lookupKey(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return entry, nil
+}
+
+func (m *memFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	key := lookupKey(name)
+	if _, ok := m.files[key]; !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	childPaths := append([]string(nil), m.children[key]...)
+	sort.Strings(childPaths)
+
+	entries := make([]fs.DirEntry, 0, len(childPaths))
+	for _, cp := range childPaths {
+		entries = append(entries, m.files[cp])
+	}
+	return entries, nil
+}
+
+func lookupKey(name string) string {
+	if name == "." {
+		return ""
+	}
+	return name
+}
+
+// memFileEntry is both the fs.FileInfo and fs.DirEntry for a memFS node.
+type memFileEntry struct {
+	name    string
+	isDir   bool
+	content []byte
+	modTime time.Time
+}
+
+func (e *memFileEntry) Name() string { return e.name }
+func (e *memFileEntry) Size() int64  { return int64(len(e.content)) }
+func (e *memFileEntry) Mode() fs.FileMode {
+	if e.isDir {
+		return fs.ModeDir | 0o555
+	}
+	return 0o444
+}
+func (e *memFileEntry) ModTime() time.Time         { return e.modTime }
+func (e *memFileEntry) IsDir() bool                { return e.isDir }
+func (e *memFileEntry) Sys() any                   { return nil }
+func (e *memFileEntry) Type() fs.FileMode          { return e.Mode().Type() }
+func (e *memFileEntry) Info() (fs.FileInfo, error) { return e, nil }
+
+// memOpenFile is the fs.File handle returned by memFS.Open for regular files.
+type memOpenFile struct {
+	entry *memFileEntry
+	*bytes.Reader
+}
+
+func (f *memOpenFile) Stat() (fs.FileInfo, error) { return f.entry, nil }
+func (f *memOpenFile) Close() error               { return nil }
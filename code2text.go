@@ -8,7 +8,9 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"sync/atomic"
 	"unicode/utf8"
 
 	"github.com/spf13/cobra"
@@ -19,6 +21,11 @@ var (
 	sizeThreshold  float64 // in MB
 	extensionsStr  string
 	excludeDirsStr string
+	includeStr     string
+	excludeStr     string
+	inputFlag      string
+	jobs           int
+	formatStr      string
 )
 
 var logger *slog.Logger
@@ -82,19 +89,35 @@ var defaultExcludeDirs = map[string]struct{}{
 }
 
 var rootCmd = &cobra.Command{
-	Use:   "code2txt",
+	Use:   "code2txt [path]",
 	Short: "Concatenates code files into a single text file.",
-	Long: `code2txt scans the current directory and its subdirectories,
-applying filters for file type, size, and binary content,
-then concatenates the results into a single text file.
+	Args:  cobra.MaximumNArgs(1),
+	Long: `code2txt scans a directory (or reads straight from a .zip/.tar/.tar.gz/.tar.bz2
+archive) and its subdirectories, applying filters for file type, size, and
+binary content, then concatenates the results into a single text file.
+
+The scan target is the current directory by default; pass a path or archive
+as the positional argument, or via --input.
 
 Examples:
   code2txt
   code2txt -o project_src.txt
   code2txt -t 1 -extensions .config,.script
-  code2txt -exclude-dirs test_data,temp_files`,
+  code2txt -exclude-dirs test_data,temp_files
+  code2txt -exclude '*.min.js,build/**/*.map'
+  code2txt -exclude '*.test.js' -include '!critical.test.js'
+  code2txt ./release-v1.2.0.tar.gz -o release-v1.2.0.txt
+  code2txt --format md -o project_src.md
+
+code2txt honors any .gitignore files found while walking (nearest
+ancestor wins, "!" negation supported) in addition to --include/--exclude.
+
+--format controls how concatenated files are rendered: "plain" (default)
+writes the original banner-delimited text, "md" writes a fenced code
+block per file, "jsonl" writes one JSON object per file, and "xml" wraps
+each file in a <file> element.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return performCodeConcatenation()
+		return performCodeConcatenation(resolveInputPath(args))
 	},
 }
 
@@ -103,13 +126,34 @@ func init() {
 	rootCmd.Flags().Float64VarP(&sizeThreshold, "threshold", "t", 0.5, "Set file size threshold in MB (0 or negative to disable)")
 	rootCmd.Flags().StringVar(&extensionsStr, "extensions", "", "Comma-separated list of additional code file extensions to include (e.g., .txt,.log)")
 	rootCmd.Flags().StringVar(&excludeDirsStr, "exclude-dirs", "", "Comma-separated list of directories to exclude (e.g., my_build,custom_assets)")
+	rootCmd.Flags().StringVar(&includeStr, "include", "", "Comma-separated gitignore-style globs to force-include (e.g., '!critical.test.js'; applied after all other filters, but cannot resurrect paths under a directory excluded by --exclude-dirs or a dir-only --exclude/.gitignore pattern)")
+	rootCmd.Flags().StringVar(&excludeStr, "exclude", "", "Comma-separated gitignore-style globs to exclude (e.g., *.min.js,build/**/*.map)")
+	rootCmd.Flags().StringVar(&inputFlag, "input", "", "Directory or archive (.zip, .tar, .tar.gz, .tar.bz2) to scan; overrides the positional argument")
+	rootCmd.Flags().IntVar(&jobs, "jobs", runtime.NumCPU(), "Number of files to process in parallel")
+	rootCmd.Flags().StringVar(&formatStr, "format", "plain", "Output format: plain, md, jsonl, or xml")
 
 	logger = slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
 		Level: slog.LevelInfo,
 	}))
 }
 
-func performCodeConcatenation() error {
+// resolveInputPath picks the scan target: --input wins, then the
+// positional argument, defaulting to the current directory.
+func resolveInputPath(args []string) string {
+	if inputFlag != "" {
+		return inputFlag
+	}
+	if len(args) > 0 {
+		return args[0]
+	}
+	return "."
+}
+
+func performCodeConcatenation(inputPath string) error {
+	if err := validateFormat(formatStr); err != nil {
+		return err
+	}
+
 	currentCodeFileExtensions := make(map[string]struct{})
 	for k, v := range codeFileExtensions {
 		currentCodeFileExtensions[k] = v
@@ -144,113 +188,178 @@ func performCodeConcatenation() error {
 		}
 	}
 
-	startDir, err := os.Getwd()
+	rootPatterns := dirNamePatterns(finalExcludeDirs)
+	cliPatterns := append(compilePatternList(excludeStr, false), compilePatternList(includeStr, true)...)
+
+	fsys, displayRoot, rootDirAbs, cleanup, err := openInputFS(inputPath)
 	if err != nil {
-		return fmt.Errorf("error getting current directory: %w", err)
+		return err
 	}
+	defer cleanup()
 
 	absOutputFile, err := filepath.Abs(outputFile)
 	if err != nil {
 		return fmt.Errorf("error resolving output file path: %w", err)
 	}
 
-	var contentBuilder strings.Builder
-	var processedFilesCount int
-	var skippedFilesCount int
+	logger.Info("Scanning input", "path", displayRoot)
+
+	var processedFilesCount int64
+	var skippedFilesCount int64
+
+	workerCount := jobs
+	if workerCount < 1 {
+		workerCount = 1
+	}
 
 	thresholdBytes := int64(sizeThreshold * 1024 * 1024)
 	if sizeThreshold <= 0 {
 		thresholdBytes = -1
 	}
 
-	walkErr := filepath.WalkDir(startDir, func(path string, d fs.DirEntry, errInWalk error) error {
-		if errInWalk != nil {
-			logger.Warn("Error accessing path, skipping", "path", path, "error", errInWalk)
-			if d != nil && d.IsDir() {
-				return filepath.SkipDir
+	ignoreTree := newIgnoreTree(rootPatterns, cliPatterns)
+
+	// The walker applies the cheap, stateful filters (gitignore/glob rules,
+	// extension, size) and feeds everything that survives to the worker
+	// pool as a candidateItem; it never touches file content itself.
+	candidates := make(chan candidateItem, workerCount)
+	var walkErr error
+	go func() {
+		defer close(candidates)
+		seq := 0
+		walkErr = fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, errInWalk error) error {
+			if errInWalk != nil {
+				logger.Warn("Error accessing path, skipping", "path", path, "error", errInWalk)
+				if d != nil && d.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
 			}
-			return nil
-		}
 
-		absPath, err := filepath.Abs(path)
-		if err != nil {
-			logger.Warn("Could not get absolute path, skipping", "path", path, "error", err)
-			skippedFilesCount++
-			return nil
-		}
+			relPath := path
+			if relPath == "." {
+				relPath = ""
+			}
 
-		if absPath == absOutputFile {
-			skippedFilesCount++
-			return nil
-		}
+			if rootDirAbs != "" {
+				absPath, err := filepath.Abs(filepath.Join(rootDirAbs, filepath.FromSlash(relPath)))
+				if err == nil && absPath == absOutputFile {
+					atomic.AddInt64(&skippedFilesCount, 1)
+					return nil
+				}
+			}
 
-		if d.IsDir() {
-			dirName := d.Name()
-			if _, shouldExclude := finalExcludeDirs[dirName]; shouldExclude {
-				return filepath.SkipDir
+			if d.IsDir() {
+				if path != "." && ignoreTree.ignored(relPath, true) {
+					return fs.SkipDir
+				}
+				ignoreTree.enter(fsys, relPath)
+				return nil
 			}
-			return nil
-		}
 
-		fileInfo, err := d.Info()
-		if err != nil {
-			logger.Warn("Error getting file info, skipping", "path", path, "error", err)
-			skippedFilesCount++
-			return nil
-		}
+			if ignoreTree.ignored(relPath, false) {
+				atomic.AddInt64(&skippedFilesCount, 1)
+				return nil
+			}
+
+			fileInfo, err := d.Info()
+			if err != nil {
+				logger.Warn("Error getting file info, skipping", "path", path, "error", err)
+				atomic.AddInt64(&skippedFilesCount, 1)
+				return nil
+			}
 
-		isCode := false
-		fileName := d.Name()
-		fileExt := filepath.Ext(fileName)
+			isCode := false
+			fileName := d.Name()
+			fileExt := filepath.Ext(fileName)
 
-		if _, ok := currentCodeFileExtensions[fileName]; ok {
-			isCode = true
-		} else if fileExt != "" {
-			if _, ok := currentCodeFileExtensions[fileExt]; ok {
+			if _, ok := currentCodeFileExtensions[fileName]; ok {
 				isCode = true
+			} else if fileExt != "" {
+				if _, ok := currentCodeFileExtensions[fileExt]; ok {
+					isCode = true
+				}
 			}
-		}
 
-		if !isCode {
-			skippedFilesCount++
-			return nil
-		}
+			if !isCode {
+				atomic.AddInt64(&skippedFilesCount, 1)
+				return nil
+			}
 
-		if thresholdBytes > 0 && fileInfo.Size() > thresholdBytes {
-			skippedFilesCount++
-			return nil
-		}
+			if thresholdBytes > 0 && fileInfo.Size() > thresholdBytes {
+				atomic.AddInt64(&skippedFilesCount, 1)
+				return nil
+			}
 
-		isBin, binCheckErr := isBinary(path)
-		if binCheckErr != nil {
-			logger.Warn("Could not check if file is binary, skipping", "path", path, "error", binCheckErr)
-			skippedFilesCount++
+			candidates <- candidateItem{seq: seq, path: path}
+			seq++
 			return nil
+		})
+	}()
+
+	// read does the expensive per-file work (binary sniff + full read) and
+	// runs concurrently across the worker pool; handle is only ever called
+	// from the single result-ordering goroutine, so it owns the writer and
+	// counters without needing a lock.
+	read := func(c candidateItem) resultItem {
+		isBin, binCheckErr := isBinary(fsys, c.path)
+		if binCheckErr != nil {
+			logger.Warn("Could not check if file is binary, skipping", "path", c.path, "error", binCheckErr)
+			return resultItem{seq: c.seq}
 		}
 		if isBin {
-			skippedFilesCount++
-			return nil
+			return resultItem{seq: c.seq}
 		}
 
-		fileContent, err := os.ReadFile(path)
+		fileContent, err := fs.ReadFile(fsys, c.path)
 		if err != nil {
-			logger.Warn("Error reading file, skipping", "path", path, "error", err)
-			skippedFilesCount++
-			return nil
+			logger.Warn("Error reading file, skipping", "path", c.path, "error", err)
+			return resultItem{seq: c.seq}
+		}
+
+		return resultItem{seq: c.seq, path: c.path, content: fileContent, include: true}
+	}
+
+	var outFileHandle *os.File
+	var writer *bufio.Writer
+	var emitter Emitter
+	var openErr error
+	handle := func(r resultItem) {
+		if !r.include {
+			atomic.AddInt64(&skippedFilesCount, 1)
+			return
 		}
 
-		relativePath, _ := filepath.Rel(startDir, path)
+		if openErr != nil {
+			atomic.AddInt64(&skippedFilesCount, 1)
+			return
+		}
 
-		contentBuilder.WriteString(fmt.Sprintf("\n%s\n", strings.Repeat("=", 80)))
-		contentBuilder.WriteString(fmt.Sprintf("File: %s\n", relativePath))
-		contentBuilder.WriteString(fmt.Sprintf("%s\n\n", strings.Repeat("=", 80)))
-		contentBuilder.Write(fileContent)
-		contentBuilder.WriteString("\n")
+		if writer == nil {
+			f, err := os.Create(absOutputFile)
+			if err != nil {
+				openErr = err
+				return
+			}
+			outFileHandle = f
+			writer = bufio.NewWriter(f)
+			emitter = newEmitter(formatStr, writer)
+			if openErr = emitter.Begin(); openErr != nil {
+				return
+			}
+		}
 
-		processedFilesCount++
-		fmt.Fprintf(os.Stdout, "\rProcessed: %d, Skipped: %d", processedFilesCount, skippedFilesCount)
-		return nil
-	})
+		if err := emitter.WriteFile(r.path, r.content); err != nil {
+			logger.Warn("Error writing file to output, skipping", "path", r.path, "error", err)
+			atomic.AddInt64(&skippedFilesCount, 1)
+			return
+		}
+
+		processed := atomic.AddInt64(&processedFilesCount, 1)
+		fmt.Fprintf(os.Stdout, "\rProcessed: %d, Skipped: %d", processed, atomic.LoadInt64(&skippedFilesCount))
+	}
+
+	processCandidates(candidates, workerCount, read, handle)
 
 	fmt.Fprintln(os.Stdout)
 
@@ -258,7 +367,11 @@ func performCodeConcatenation() error {
 		logger.Warn("Error encountered during directory walk", "error", walkErr)
 	}
 
-	if contentBuilder.Len() == 0 {
+	if openErr != nil {
+		return fmt.Errorf("error creating output file %q: %w", absOutputFile, openErr)
+	}
+
+	if writer == nil {
 		logger.Info("No content was generated.")
 		if processedFilesCount == 0 && skippedFilesCount > 0 {
 			logger.Info("File processing summary", "processed", 0, "skipped", skippedFilesCount)
@@ -266,27 +379,23 @@ func performCodeConcatenation() error {
 		}
 		return nil
 	}
+	defer outFileHandle.Close()
 
-	outFile, err := os.Create(absOutputFile)
-	if err != nil {
-		return fmt.Errorf("error creating output file %q: %w", absOutputFile, err)
+	if err := emitter.End(); err != nil {
+		return fmt.Errorf("error finalizing output file: %w", err)
 	}
-	defer outFile.Close()
 
-	writer := bufio.NewWriter(outFile)
-	_, err = writer.WriteString(contentBuilder.String())
-	if err != nil {
+	if err := writer.Flush(); err != nil {
 		return fmt.Errorf("error writing to output file: %w", err)
 	}
-	writer.Flush()
 
 	logger.Info("Processing complete", "processed", processedFilesCount, "skipped", skippedFilesCount)
 	logger.Info("Output saved", "path", absOutputFile)
 	return nil
 }
 
-func isBinary(filePath string) (bool, error) {
-	file, err := os.Open(filePath)
+func isBinary(fsys fs.FS, filePath string) (bool, error) {
+	file, err := fsys.Open(filePath)
 	if err != nil {
 		return false, fmt.Errorf("opening file: %w", err)
 	}
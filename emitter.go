@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Emitter renders the stream of scanned files into one of the supported
+// --format outputs. Begin/End bracket the whole run (e.g. an XML root
+// element); WriteFile is called once per included file, in walk order.
+type Emitter interface {
+	Begin() error
+	WriteFile(relPath string, content []byte) error
+	End() error
+}
+
+// supportedFormats lists the valid --format values, in help-text order.
+var supportedFormats = []string{"plain", "md", "jsonl", "xml"}
+
+// validateFormat rejects an unknown --format value before any scanning
+// happens, so a typo fails fast instead of after a full walk.
+func validateFormat(format string) error {
+	if format == "" {
+		return nil
+	}
+	for _, f := range supportedFormats {
+		if format == f {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown --format %q (want one of: %s)", format, strings.Join(supportedFormats, ", "))
+}
+
+// newEmitter builds the Emitter for the given --format value, writing
+// through w. format must already be validated; an empty value means
+// "plain".
+func newEmitter(format string, w *bufio.Writer) Emitter {
+	switch format {
+	case "md":
+		return &markdownEmitter{w: w}
+	case "jsonl":
+		return &jsonlEmitter{w: w}
+	case "xml":
+		return &xmlEmitter{w: w}
+	default:
+		return &plainEmitter{w: w}
+	}
+}
+
+// langByExt maps file extensions (and a few extension-less filenames) to
+// the language tag used for Markdown fenced code blocks.
+var langByExt = map[string]string{
+	".go": "go", ".py": "python", ".js": "javascript", ".jsx": "jsx", ".ts": "typescript", ".tsx": "tsx",
+	".java": "java", ".c": "c", ".cpp": "cpp", ".h": "c", ".hpp": "cpp", ".rs": "rust", ".html": "html",
+	".css": "css", ".scss": "scss", ".less": "less", ".json": "json", ".xml": "xml", ".yaml": "yaml",
+	".yml": "yaml", ".md": "markdown", ".sh": "bash", ".bash": "bash", ".zsh": "zsh", ".rb": "ruby",
+	".php": "php", ".swift": "swift", ".kt": "kotlin", ".kts": "kotlin", ".gradle": "groovy", ".pl": "perl",
+	".pm": "perl", ".lua": "lua", ".sql": "sql", ".r": "r", ".dart": "dart", ".cs": "csharp", ".fs": "fsharp",
+	".vb": "vbnet", ".scala": "scala", ".clj": "clojure", ".cljs": "clojure", ".ex": "elixir", ".exs": "elixir",
+	".elm": "elm", ".hs": "haskell", ".tf": "hcl", ".tfvars": "hcl", ".hcl": "hcl", ".ini": "ini",
+	".toml": "toml", ".vue": "vue", ".svelte": "svelte", ".graphql": "graphql", ".gql": "graphql",
+	"Dockerfile": "dockerfile", "Makefile": "makefile",
+}
+
+// langForFile returns the Markdown/highlighting language tag for relPath,
+// falling back to the bare extension or "text" if neither is known.
+func langForFile(relPath string) string {
+	name := filepath.Base(relPath)
+	if lang, ok := langByExt[name]; ok {
+		return lang
+	}
+	ext := filepath.Ext(name)
+	if lang, ok := langByExt[ext]; ok {
+		return lang
+	}
+	if ext != "" {
+		return strings.TrimPrefix(ext, ".")
+	}
+	return "text"
+}
+
+// plainEmitter reproduces the original `===`-banner format.
+type plainEmitter struct {
+	w *bufio.Writer
+}
+
+func (e *plainEmitter) Begin() error { return nil }
+
+func (e *plainEmitter) WriteFile(relPath string, content []byte) error {
+	sep := strings.Repeat("=", 80)
+	fmt.Fprintf(e.w, "\n%s\nFile: %s\n%s\n\n", sep, relPath, sep)
+	e.w.Write(content)
+	e.w.WriteString("\n")
+	return nil
+}
+
+func (e *plainEmitter) End() error { return nil }
+
+// markdownEmitter renders each file as a level-2 heading followed by a
+// fenced code block tagged with its language, so downstream Markdown
+// renderers and LLM prompts syntax-highlight correctly.
+type markdownEmitter struct {
+	w *bufio.Writer
+}
+
+func (e *markdownEmitter) Begin() error { return nil }
+
+func (e *markdownEmitter) WriteFile(relPath string, content []byte) error {
+	fmt.Fprintf(e.w, "## %s\n\n```%s\n", relPath, langForFile(relPath))
+	e.w.Write(content)
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		e.w.WriteString("\n")
+	}
+	e.w.WriteString("```\n\n")
+	return nil
+}
+
+func (e *markdownEmitter) End() error { return nil }
+
+// jsonlEmitter writes one JSON object per file, newline-delimited, so the
+// output is trivially streamable and greppable.
+type jsonlEmitter struct {
+	w *bufio.Writer
+}
+
+type jsonlRecord struct {
+	Path    string `json:"path"`
+	Size    int    `json:"size"`
+	Lang    string `json:"lang"`
+	SHA256  string `json:"sha256"`
+	Content string `json:"content"`
+}
+
+func (e *jsonlEmitter) Begin() error { return nil }
+
+func (e *jsonlEmitter) WriteFile(relPath string, content []byte) error {
+	if !utf8.Valid(content) {
+		return fmt.Errorf("content is not valid UTF-8, cannot embed in a JSON string")
+	}
+
+	sum := sha256.Sum256(content)
+	line, err := json.Marshal(jsonlRecord{
+		Path:    relPath,
+		Size:    len(content),
+		Lang:    langForFile(relPath),
+		SHA256:  hex.EncodeToString(sum[:]),
+		Content: string(content),
+	})
+	if err != nil {
+		return fmt.Errorf("marshaling jsonl record for %q: %w", relPath, err)
+	}
+	e.w.Write(line)
+	e.w.WriteString("\n")
+	return nil
+}
+
+func (e *jsonlEmitter) End() error { return nil }
+
+// xmlEmitter wraps each file in a <file path="..." lang="..."> element
+// with CDATA-escaped content inside a <code2txt> root, a common
+// convention for feeding structured source context to LLMs.
+type xmlEmitter struct {
+	w *bufio.Writer
+}
+
+func (e *xmlEmitter) Begin() error {
+	_, err := e.w.WriteString("<code2txt>\n")
+	return err
+}
+
+func (e *xmlEmitter) WriteFile(relPath string, content []byte) error {
+	if !utf8.Valid(content) {
+		return fmt.Errorf("content is not valid UTF-8, cannot embed in an XML document")
+	}
+
+	fmt.Fprintf(e.w, "  <file path=\"%s\" lang=\"%s\"><![CDATA[", xmlAttr(relPath), xmlAttr(langForFile(relPath)))
+	e.w.Write(bytes.ReplaceAll(content, []byte("]]>"), []byte("]]]]><![CDATA[>")))
+	e.w.WriteString("]]></file>\n")
+	return nil
+}
+
+func (e *xmlEmitter) End() error {
+	_, err := e.w.WriteString("</code2txt>\n")
+	return err
+}
+
+// xmlAttr escapes s for safe use as an XML attribute value.
+func xmlAttr(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
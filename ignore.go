@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignorePattern is a single compiled gitignore-style rule. Rules are
+// evaluated in declaration order and the last one to match a path wins,
+// mirroring git's own semantics (including "!" negation).
+type ignorePattern struct {
+	negate  bool
+	dirOnly bool
+	regex   *regexp.Regexp
+}
+
+// compileIgnorePattern compiles one line of a .gitignore file (or one
+// --include/--exclude entry) into an ignorePattern. baseDir is the
+// slash-separated path, relative to the walk root, of the directory the
+// pattern was declared in ("" for the walk root itself); patterns that
+// contain a "/" other than a trailing one are anchored to baseDir, all
+// others may match at any depth beneath it.
+func compileIgnorePattern(pattern, baseDir string) *ignorePattern {
+	pattern = strings.TrimRight(pattern, " \t")
+	if pattern == "" || strings.HasPrefix(pattern, "#") {
+		return nil
+	}
+
+	negate := false
+	if strings.HasPrefix(pattern, "!") {
+		negate = true
+		pattern = pattern[1:]
+	}
+
+	dirOnly := false
+	if strings.HasSuffix(pattern, "/") {
+		dirOnly = true
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+	if pattern == "" {
+		return nil
+	}
+
+	anchored := strings.HasPrefix(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	anchored = anchored || strings.Contains(pattern, "/")
+
+	regexSrc := globToRegex(pattern)
+	if baseDir != "" {
+		regexSrc = regexp.QuoteMeta(baseDir+"/") + regexSrc
+	}
+	if anchored {
+		regexSrc = "^" + regexSrc + "$"
+	} else {
+		regexSrc = "(^|^.*/)" + regexSrc + "$"
+	}
+
+	re, err := regexp.Compile(regexSrc)
+	if err != nil {
+		logger.Warn("Skipping invalid ignore pattern", "pattern", pattern, "error", err)
+		return nil
+	}
+	return &ignorePattern{negate: negate, dirOnly: dirOnly, regex: re}
+}
+
+// globToRegex translates a single gitignore glob (no leading/trailing
+// slash handling, that's done by the caller) into a regex fragment.
+// "**" matches across path segments, "*" and "?" stay within one segment,
+// and "[...]" character classes are passed through verbatim.
+func globToRegex(pattern string) string {
+	var b strings.Builder
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		switch c := runes[i]; c {
+		case '*':
+			if i+1 < len(runes) && runes[i+1] == '*' {
+				if i+2 < len(runes) && runes[i+2] == '/' {
+					// "**/" matches zero or more whole path segments, so the
+					// boundary slash it consumes has to be put back as part
+					// of the match, not dropped.
+					b.WriteString("(?:.*/)?")
+					i += 2
+				} else {
+					b.WriteString(".*")
+					i++
+				}
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			j := i + 1
+			for j < len(runes) && runes[j] != ']' {
+				j++
+			}
+			if j < len(runes) {
+				b.WriteString(string(runes[i : j+1]))
+				i = j
+			} else {
+				b.WriteString(regexp.QuoteMeta(string(c)))
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// compilePatternList compiles a comma-separated list of gitignore-style
+// globs (as accepted by --include/--exclude) relative to the walk root.
+// forceNegate is set for --include, whose entries mean "never ignore",
+// regardless of whether the user already prefixed them with "!".
+func compilePatternList(raw string, forceNegate bool) []*ignorePattern {
+	if raw == "" {
+		return nil
+	}
+	var patterns []*ignorePattern
+	for _, p := range strings.Split(raw, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if forceNegate && !strings.HasPrefix(p, "!") {
+			p = "!" + p
+		}
+		if compiled := compileIgnorePattern(p, ""); compiled != nil {
+			patterns = append(patterns, compiled)
+		}
+	}
+	return patterns
+}
+
+// dirNamePatterns turns a set of bare directory names (the historical
+// defaultExcludeDirs/--exclude-dirs basename matches) into unanchored,
+// directory-only ignore patterns, so they keep matching a directory of
+// that name at any depth.
+func dirNamePatterns(names map[string]struct{}) []*ignorePattern {
+	patterns := make([]*ignorePattern, 0, len(names))
+	for name := range names {
+		if compiled := compileIgnorePattern(name+"/", ""); compiled != nil {
+			patterns = append(patterns, compiled)
+		}
+	}
+	return patterns
+}
+
+// loadGitignore reads relDir/.gitignore from fsys, if present, and
+// compiles its rules with baseDir set to relDir (relDir's path relative
+// to the walk root, "" for the root itself).
+func loadGitignore(fsys fs.FS, relDir string) []*ignorePattern {
+	giPath := ".gitignore"
+	if relDir != "" {
+		giPath = path.Join(relDir, ".gitignore")
+	}
+
+	content, err := fs.ReadFile(fsys, giPath)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			logger.Warn("Could not read .gitignore, skipping", "path", giPath, "error", err)
+		}
+		return nil
+	}
+
+	var patterns []*ignorePattern
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		if compiled := compileIgnorePattern(scanner.Text(), relDir); compiled != nil {
+			patterns = append(patterns, compiled)
+		}
+	}
+	return patterns
+}
+
+// matchIgnored reports whether relPath (slash-separated, relative to the
+// walk root) is ignored by patterns, applying git's last-match-wins rule.
+func matchIgnored(patterns []*ignorePattern, relPath string, isDir bool) bool {
+	relPath = filepath.ToSlash(relPath)
+	ignored := false
+	for _, p := range patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if p.regex.MatchString(relPath) {
+			ignored = !p.negate
+		}
+	}
+	return ignored
+}
+
+// ignoreTree tracks the cumulative set of ignore patterns in effect for
+// each directory visited during the walk (parent patterns plus that
+// directory's own .gitignore, in the order git would apply them so
+// nearer, more specific rules win), plus a set of CLI-supplied
+// --include/--exclude patterns that always apply last and so take final
+// precedence over anything found on disk.
+type ignoreTree struct {
+	cumulative map[string][]*ignorePattern
+	cli        []*ignorePattern
+}
+
+func newIgnoreTree(rootPatterns, cliPatterns []*ignorePattern) *ignoreTree {
+	return &ignoreTree{
+		cumulative: map[string][]*ignorePattern{"": rootPatterns},
+		cli:        cliPatterns,
+	}
+}
+
+// enter loads relDir/.gitignore (if any) from fsys and records the
+// cumulative pattern set for it, keyed by its slash path relative to the
+// walk root.
+func (t *ignoreTree) enter(fsys fs.FS, relDir string) {
+	parent := ""
+	if idx := strings.LastIndexByte(relDir, '/'); idx >= 0 {
+		parent = relDir[:idx]
+	}
+	cumulative := t.cumulative[parent]
+	if own := loadGitignore(fsys, relDir); len(own) > 0 {
+		combined := make([]*ignorePattern, 0, len(cumulative)+len(own))
+		combined = append(combined, cumulative...)
+		combined = append(combined, own...)
+		cumulative = combined
+	}
+	t.cumulative[relDir] = cumulative
+}
+
+// ignored reports whether relPath should be excluded, checking it against
+// the pattern set effective in its parent directory plus the CLI patterns.
+func (t *ignoreTree) ignored(relPath string, isDir bool) bool {
+	relPath = filepath.ToSlash(relPath)
+	parent := ""
+	if idx := strings.LastIndexByte(relPath, '/'); idx >= 0 {
+		parent = relPath[:idx]
+	}
+	patterns := t.cumulative[parent]
+	if len(t.cli) > 0 {
+		combined := make([]*ignorePattern, 0, len(patterns)+len(t.cli))
+		combined = append(combined, patterns...)
+		combined = append(combined, t.cli...)
+		patterns = combined
+	}
+	return matchIgnored(patterns, relPath, isDir)
+}
@@ -0,0 +1,95 @@
+package main
+
+import "testing"
+
+func TestCompileIgnorePatternMatching(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		path    string
+		isDir   bool
+		want    bool
+	}{
+		{"plain basename matches anywhere", "*.log", "debug.log", false, true},
+		{"plain basename matches nested", "*.log", "sub/debug.log", false, true},
+		{"anchored leading slash only matches root", "/build", "build", true, true},
+		{"anchored leading slash does not match nested", "/build", "sub/build", true, false},
+		{"double-star matches across segments", "vendor/**/*.go", "vendor/a/b/c.go", false, true},
+		{"single star stays within a segment", "*.go", "sub/dir/c.go", false, true},
+		{"dir-only pattern does not match a file", "vendor/", "vendor", false, false},
+		{"dir-only pattern matches a dir", "vendor/", "vendor", true, true},
+		{"character class", "file[0-9].txt", "file3.txt", false, true},
+		{"character class non-match", "file[0-9].txt", "fileA.txt", false, false},
+		{"path with explicit slash is anchored to baseDir", "sub/c.go", "other/sub/c.go", false, false},
+		{"leading **/ matches at the root", "**/node_modules", "node_modules", true, true},
+		{"leading **/ matches nested", "**/node_modules", "a/b/node_modules", true, true},
+		{"leading **/ does not swallow the preceding segment boundary", "**/node_modules", "my_node_modules", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := compileIgnorePattern(tt.pattern, "")
+			if p == nil {
+				t.Fatalf("compileIgnorePattern(%q) returned nil", tt.pattern)
+			}
+			if got := matchIgnored([]*ignorePattern{p}, tt.path, tt.isDir); got != tt.want {
+				t.Errorf("matchIgnored(%q, %q, isDir=%v) = %v, want %v", tt.pattern, tt.path, tt.isDir, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestMatchIgnoredLastMatchWins checks that later patterns override
+// earlier ones, including re-including a file via "!" negation, matching
+// git's own precedence rules.
+func TestMatchIgnoredLastMatchWins(t *testing.T) {
+	patterns := []*ignorePattern{
+		compileIgnorePattern("*.log", ""),
+		compileIgnorePattern("!debug.log", ""),
+	}
+
+	if matchIgnored(patterns, "debug.log", false) {
+		t.Error("debug.log should be re-included by the later negated pattern")
+	}
+	if !matchIgnored(patterns, "other.log", false) {
+		t.Error("other.log should still be ignored")
+	}
+}
+
+// TestDirNamePatternsMatchAnyDepth checks that a bare directory name (as
+// produced by defaultExcludeDirs/--exclude-dirs) keeps matching a
+// directory of that name no matter how deep it is.
+func TestDirNamePatternsMatchAnyDepth(t *testing.T) {
+	patterns := dirNamePatterns(map[string]struct{}{"vendor": {}})
+	if len(patterns) != 1 {
+		t.Fatalf("got %d patterns, want 1", len(patterns))
+	}
+
+	if !matchIgnored(patterns, "vendor", true) {
+		t.Error("vendor at the root should be ignored")
+	}
+	if !matchIgnored(patterns, "pkg/vendor", true) {
+		t.Error("vendor nested under pkg should be ignored")
+	}
+	if matchIgnored(patterns, "vendor", false) {
+		t.Error("a file named vendor should not match a dir-only pattern")
+	}
+}
+
+// TestIgnoreTreeCLIPatternsTakeFinalPrecedence checks that CLI
+// --include/--exclude patterns are consulted after a directory's own
+// cumulative (gitignore) patterns, so they can override a per-file
+// gitignore exclude.
+func TestIgnoreTreeCLIPatternsTakeFinalPrecedence(t *testing.T) {
+	root := compileIgnorePattern("*.log", "")
+	cli := compilePatternList("!debug.log", true)
+
+	tree := newIgnoreTree([]*ignorePattern{root}, cli)
+
+	if tree.ignored("debug.log", false) {
+		t.Error("--include '!debug.log' should override the root *.log exclude")
+	}
+	if !tree.ignored("other.log", false) {
+		t.Error("other.log should still be excluded by the root pattern")
+	}
+}
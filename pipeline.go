@@ -0,0 +1,89 @@
+package main
+
+import (
+	"container/heap"
+	"sync"
+)
+
+// candidateItem is a file that passed the cheap walk-time filters
+// (ignore rules, extension, size threshold) and is ready for the
+// expensive per-file work. seq is its position in walk order.
+type candidateItem struct {
+	seq  int
+	path string
+}
+
+// resultItem is what a worker produces for one candidateItem. include is
+// false when the file turned out to be binary or unreadable, in which
+// case path/content are unused but the seq still has to flow through the
+// heap so the writer can advance past it. Formatting the file for output
+// is left to the caller's handle func, so the same pipeline serves every
+// Emitter.
+type resultItem struct {
+	seq     int
+	path    string
+	content []byte
+	include bool
+}
+
+// processCandidates runs `jobs` workers over candidates, each calling
+// read to do the per-file work, then reassembles the results in walk
+// order (via orderResults) and calls handle on each one in that order.
+// It returns once every candidate has been read and handled.
+func processCandidates(candidates <-chan candidateItem, jobs int, read func(candidateItem) resultItem, handle func(resultItem)) {
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	results := make(chan resultItem, jobs)
+	var wg sync.WaitGroup
+	wg.Add(jobs)
+	for i := 0; i < jobs; i++ {
+		go func() {
+			defer wg.Done()
+			for c := range candidates {
+				results <- read(c)
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	orderResults(results, handle)
+}
+
+// orderResults buffers out-of-order results in a min-heap keyed by seq
+// and calls handle on each one strictly in ascending seq order, so
+// concurrent workers can finish in any order without affecting output.
+func orderResults(results <-chan resultItem, handle func(resultItem)) {
+	pending := &resultHeap{}
+	next := 0
+	for r := range results {
+		heap.Push(pending, r)
+		for pending.Len() > 0 && (*pending)[0].seq == next {
+			handle(heap.Pop(pending).(resultItem))
+			next++
+		}
+	}
+}
+
+type resultHeap []resultItem
+
+func (h resultHeap) Len() int           { return len(h) }
+func (h resultHeap) Less(i, j int) bool { return h[i].seq < h[j].seq }
+func (h resultHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *resultHeap) Push(x any) {
+	*h = append(*h, x.(resultItem))
+}
+
+func (h *resultHeap) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// TestOrderResultsRestoresSeqOrder feeds results to orderResults in a
+// shuffled order (as concurrent workers would produce them) and checks
+// that handle still sees them strictly in ascending seq order.
+func TestOrderResultsRestoresSeqOrder(t *testing.T) {
+	const n = 200
+
+	shuffled := make([]int, n)
+	for i := range shuffled {
+		shuffled[i] = i
+	}
+	rand.New(rand.NewSource(1)).Shuffle(n, func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+
+	results := make(chan resultItem, n)
+	for _, seq := range shuffled {
+		results <- resultItem{seq: seq}
+	}
+	close(results)
+
+	var got []int
+	orderResults(results, func(r resultItem) {
+		got = append(got, r.seq)
+	})
+
+	if len(got) != n {
+		t.Fatalf("handled %d results, want %d", len(got), n)
+	}
+	for i, seq := range got {
+		if seq != i {
+			t.Fatalf("got[%d] = %d, want %d", i, seq, i)
+		}
+	}
+}
+
+// TestProcessCandidatesDeterministicOrder runs the full candidate ->
+// worker pool -> ordered handle pipeline with several worker counts and
+// checks the handled order always matches walk (seq) order, which is the
+// guarantee the output-byte-identical-regardless-of---jobs behavior
+// depends on.
+func TestProcessCandidatesDeterministicOrder(t *testing.T) {
+	const n = 100
+
+	for _, jobs := range []int{1, 2, 8, 32} {
+		candidates := make(chan candidateItem, n)
+		for i := 0; i < n; i++ {
+			candidates <- candidateItem{seq: i, path: string(rune('a' + i%26))}
+		}
+		close(candidates)
+
+		read := func(c candidateItem) resultItem {
+			return resultItem{seq: c.seq, path: c.path, content: []byte(c.path), include: true}
+		}
+
+		var got []int
+		handle := func(r resultItem) {
+			got = append(got, r.seq)
+		}
+
+		processCandidates(candidates, jobs, read, handle)
+
+		if len(got) != n {
+			t.Fatalf("jobs=%d: handled %d results, want %d", jobs, len(got), n)
+		}
+		for i, seq := range got {
+			if seq != i {
+				t.Fatalf("jobs=%d: got[%d] = %d, want %d", jobs, i, seq, i)
+			}
+		}
+	}
+}